@@ -0,0 +1,506 @@
+package fileutils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArchiveEntry describes one entry inside an archive, as returned by ListArchive.
+type ArchiveEntry struct {
+	Name    string
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+	IsDir   bool
+}
+
+// PackOptions configures PackDir. Compression is a pointer so that an
+// explicit request for flate.NoCompression/gzip.NoCompression (0) can be
+// told apart from leaving it unset (nil), which keeps the package default.
+type PackOptions struct {
+	Prefix         string
+	Suffix         string
+	FollowSymlinks bool
+	Deterministic  bool
+	Compression    *int
+}
+
+// UnpackOptions configures Unpack.
+type UnpackOptions struct {
+	StripComponents int
+	Overwrite       bool
+}
+
+const (
+	formatZip   = "zip"
+	formatTarGz = "targz"
+	formatTar   = "tar"
+)
+
+// archiveFormat detects the archive format from the file extension.
+func archiveFormat(path string) (string, error) {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return formatZip, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return formatTarGz, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return formatTar, nil
+	default:
+		return "", fmt.Errorf("unrecognized archive extension: %s", path)
+	}
+}
+
+// PackDir bundles the files under srcDir matching Prefix/Suffix (the same
+// semantics as FileList) into archivePath, auto-detecting the format
+// (.zip, .tar.gz, .tgz, .tar) from its extension.
+func PackDir(srcDir, archivePath string, opts PackOptions) error {
+	format, err := archiveFormat(archivePath)
+	if err != nil {
+		return err
+	}
+
+	entries, err := collectPackEntries(srcDir, opts)
+	if err != nil {
+		return err
+	}
+
+	// Write to a sibling temp file and rename into place on success, so a
+	// mid-stream error (disk full, a file vanishing during the walk) never
+	// leaves a truncated, invalid archive sitting at archivePath.
+	return withAtomicFile(archivePath, func(out *os.File) error {
+		switch format {
+		case formatZip:
+			return packZip(out, srcDir, entries, opts)
+		case formatTarGz, formatTar:
+			return packTar(out, srcDir, entries, opts, format == formatTarGz)
+		default:
+			return fmt.Errorf("unsupported archive format: %s", format)
+		}
+	}, nil)
+}
+
+type packEntry struct {
+	relPath string
+	absPath string
+	info    os.FileInfo
+}
+
+// collectPackEntries walks srcDir, keeping regular files whose base name
+// matches Prefix/Suffix (directories are always kept so the tree structure
+// is preserved) and symlinks only when FollowSymlinks is set.
+func collectPackEntries(srcDir string, opts PackOptions) ([]packEntry, error) {
+	var entries []packEntry
+
+	err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == srcDir {
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !opts.FollowSymlinks {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			// Walk reports symlinks via Lstat, so info still carries
+			// ModeSymlink and the link-target-string length as Size(); the
+			// archive entry must be built from the resolved target instead,
+			// or Unpack will reject it for having an unsafe (symlink) mode.
+			resolved, statErr := os.Stat(path)
+			if statErr != nil {
+				return statErr
+			}
+			info = resolved
+		}
+		if !info.IsDir() {
+			if !strings.HasPrefix(info.Name(), opts.Prefix) || !strings.HasSuffix(info.Name(), opts.Suffix) {
+				return nil
+			}
+		}
+
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, packEntry{relPath: filepath.ToSlash(rel), absPath: path, info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Deterministic {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].relPath < entries[j].relPath })
+	}
+	return entries, nil
+}
+
+func packZip(out io.Writer, srcDir string, entries []packEntry, opts PackOptions) error {
+	zw := zip.NewWriter(out)
+
+	// An explicit flate.NoCompression request stores entries uncompressed
+	// (zip.Store) rather than running them through a Deflate stream with
+	// compression disabled, which still carries Deflate framing overhead.
+	store := opts.Compression != nil && *opts.Compression == flate.NoCompression
+	if opts.Compression != nil && !store {
+		level := *opts.Compression
+		zw.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(w, level)
+		})
+	}
+
+	for _, e := range entries {
+		hdr, err := zip.FileInfoHeader(e.info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = e.relPath
+		if e.info.IsDir() {
+			hdr.Name += "/"
+		}
+		if store {
+			hdr.Method = zip.Store
+		} else {
+			hdr.Method = zip.Deflate
+		}
+		if opts.Deterministic {
+			hdr.Modified = time.Unix(0, 0).UTC()
+		}
+
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if e.info.IsDir() {
+			continue
+		}
+
+		if err := copyFileInto(w, e.absPath); err != nil {
+			return err
+		}
+	}
+
+	// zw.Close() writes the central directory; checked explicitly (not via
+	// defer) so a failure here is never swallowed and reported as success.
+	return zw.Close()
+}
+
+func packTar(out io.Writer, srcDir string, entries []packEntry, opts PackOptions, gzipped bool) error {
+	var gw *gzip.Writer
+	var tw *tar.Writer
+	if gzipped {
+		level := gzip.DefaultCompression
+		if opts.Compression != nil {
+			level = *opts.Compression
+		}
+		var err error
+		gw, err = gzip.NewWriterLevel(out, level)
+		if err != nil {
+			return err
+		}
+		tw = tar.NewWriter(gw)
+	} else {
+		tw = tar.NewWriter(out)
+	}
+
+	for _, e := range entries {
+		hdr, err := tar.FileInfoHeader(e.info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = e.relPath
+		if e.info.IsDir() {
+			hdr.Name += "/"
+		}
+		if opts.Deterministic {
+			hdr.ModTime = time.Unix(0, 0).UTC()
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if e.info.IsDir() {
+			continue
+		}
+
+		if err := copyFileInto(tw, e.absPath); err != nil {
+			return err
+		}
+	}
+
+	// tw.Close() writes the tar padding/footer and gw.Close() the gzip
+	// footer; both are checked explicitly so a failure here is never
+	// swallowed and reported as success.
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if gw != nil {
+		if err := gw.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFileInto(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Unpack extracts archivePath into destDir, auto-detecting the format from
+// its extension. Entries whose cleaned path would escape destDir (Zip-Slip)
+// or whose mode is not a plain file or directory are rejected.
+func Unpack(archivePath, destDir string, opts UnpackOptions) error {
+	format, err := archiveFormat(archivePath)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case formatZip:
+		return unpackZip(archivePath, destDir, opts)
+	case formatTarGz, formatTar:
+		in, err := os.Open(archivePath)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		return unpackTar(in, destDir, opts, format == formatTarGz)
+	default:
+		return fmt.Errorf("unsupported archive format: %s", format)
+	}
+}
+
+// safeJoin strips StripComponents path elements from name, rejects names
+// that escape destDir once cleaned, and returns the resolved destination path.
+func safeJoin(destDir, name string, strip int) (string, error) {
+	parts := strings.Split(filepath.ToSlash(name), "/")
+	if strip >= len(parts) {
+		return "", nil
+	}
+	parts = parts[strip:]
+	rel := filepath.Join(parts...)
+
+	target := filepath.Join(destDir, rel)
+	if rel == "." || rel == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(target, filepath.Clean(destDir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("archive entry escapes destination: %s", name)
+	}
+	return target, nil
+}
+
+func unpackZip(archivePath, destDir string, opts UnpackOptions) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		target, err := safeJoin(destDir, f.Name, opts.StripComponents)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+
+		mode := f.Mode()
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			return fmt.Errorf("archive entry has unsafe mode %v: %s", mode, f.Name)
+		}
+		if err := extractZipFile(f, target, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipFile(f *zip.File, target string, opts UnpackOptions) error {
+	if !opts.Overwrite {
+		if _, err := os.Stat(target); err == nil {
+			return fmt.Errorf("destination already exists: %s", target)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, f.Mode())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, rc)
+	return err
+}
+
+func unpackTar(in io.Reader, destDir string, opts UnpackOptions, gzipped bool) error {
+	r := in
+	if gzipped {
+		gr, err := gzip.NewReader(in)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := safeJoin(destDir, hdr.Name, opts.StripComponents)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if !opts.Overwrite {
+				if _, err := os.Stat(target); err == nil {
+					return fmt.Errorf("destination already exists: %s", target)
+				}
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			out.Close()
+		default:
+			return fmt.Errorf("archive entry has unsafe type %v: %s", hdr.Typeflag, hdr.Name)
+		}
+	}
+	return nil
+}
+
+// ListArchive returns the entries contained in archivePath without extracting them.
+func ListArchive(path string) ([]ArchiveEntry, error) {
+	format, err := archiveFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case formatZip:
+		return listZip(path)
+	case formatTarGz, formatTar:
+		return listTar(path, format == formatTarGz)
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %s", format)
+	}
+}
+
+func listZip(path string) ([]ArchiveEntry, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var out []ArchiveEntry
+	for _, f := range zr.File {
+		fi := f.FileInfo()
+		out = append(out, ArchiveEntry{
+			Name:    f.Name,
+			Size:    int64(f.UncompressedSize64),
+			Mode:    fi.Mode(),
+			ModTime: fi.ModTime(),
+			IsDir:   fi.IsDir(),
+		})
+	}
+	return out, nil
+}
+
+func listTar(path string, gzipped bool) ([]ArchiveEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gzipped {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	var out []ArchiveEntry
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ArchiveEntry{
+			Name:    hdr.Name,
+			Size:    hdr.Size,
+			Mode:    os.FileMode(hdr.Mode),
+			ModTime: hdr.ModTime,
+			IsDir:   hdr.Typeflag == tar.TypeDir,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,179 @@
+package fileutils
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackDirExplicitNoCompressionIsStored(t *testing.T) {
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "src")
+	if err := os.Mkdir(srcDir, 0o755); err != nil {
+		t.Fatalf("mkdir srcDir: %v", err)
+	}
+	// Highly compressible content, so a default-compressed entry would be
+	// much smaller than the stored (uncompressed) size.
+	payload := make([]byte, 4096)
+	if err := os.WriteFile(filepath.Join(srcDir, "a.txt"), payload, 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	noCompression := flate.NoCompression
+	archivePath := filepath.Join(dir, "stored.zip")
+	if err := PackDir(srcDir, archivePath, PackOptions{Compression: &noCompression}); err != nil {
+		t.Fatalf("PackDir: %v", err)
+	}
+
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.Name != "a.txt" {
+			continue
+		}
+		if f.CompressedSize64 != f.UncompressedSize64 {
+			t.Fatalf("expected explicit NoCompression to store a.txt uncompressed, got compressed=%d uncompressed=%d", f.CompressedSize64, f.UncompressedSize64)
+		}
+		return
+	}
+	t.Fatal("a.txt not found in archive")
+}
+
+func TestUnpackRejectsZipSlip(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "evil.zip")
+	destDir := filepath.Join(dir, "dest")
+	if err := os.Mkdir(destDir, 0o755); err != nil {
+		t.Fatalf("mkdir destDir: %v", err)
+	}
+
+	zf, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("create archive: %v", err)
+	}
+	zw := zip.NewWriter(zf)
+	w, err := zw.Create("../escaped.txt")
+	if err != nil {
+		t.Fatalf("create zip entry: %v", err)
+	}
+	if _, err := w.Write([]byte("pwned")); err != nil {
+		t.Fatalf("write zip entry: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip writer: %v", err)
+	}
+	zf.Close()
+
+	if err := Unpack(archivePath, destDir, UnpackOptions{}); err == nil {
+		t.Fatal("expected Unpack to reject a zip-slip entry, got nil error")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "escaped.txt")); !os.IsNotExist(err) {
+		t.Fatalf("zip-slip entry escaped destDir, stat err: %v", err)
+	}
+}
+
+func TestUnpackRejectsUnsafeMode(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "unsafe.zip")
+	destDir := filepath.Join(dir, "dest")
+	if err := os.Mkdir(destDir, 0o755); err != nil {
+		t.Fatalf("mkdir destDir: %v", err)
+	}
+
+	zf, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("create archive: %v", err)
+	}
+	zw := zip.NewWriter(zf)
+	hdr := &zip.FileHeader{Name: "dev.txt"}
+	hdr.SetMode(os.ModeSymlink | 0o777)
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		t.Fatalf("create zip header: %v", err)
+	}
+	if _, err := w.Write([]byte("/etc/passwd")); err != nil {
+		t.Fatalf("write zip entry: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip writer: %v", err)
+	}
+	zf.Close()
+
+	if err := Unpack(archivePath, destDir, UnpackOptions{}); err == nil {
+		t.Fatal("expected Unpack to reject an entry with an unsafe mode, got nil error")
+	}
+}
+
+func TestPackDirFollowSymlinksResolvesTargetMode(t *testing.T) {
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "src")
+	if err := os.Mkdir(srcDir, 0o755); err != nil {
+		t.Fatalf("mkdir srcDir: %v", err)
+	}
+
+	realFile := filepath.Join(srcDir, "real.txt")
+	if err := os.WriteFile(realFile, []byte("payload"), 0o644); err != nil {
+		t.Fatalf("write real file: %v", err)
+	}
+	linkFile := filepath.Join(srcDir, "link.txt")
+	if err := os.Symlink(realFile, linkFile); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	archivePath := filepath.Join(dir, "out.zip")
+	err := PackDir(srcDir, archivePath, PackOptions{FollowSymlinks: true})
+	if err != nil {
+		t.Fatalf("PackDir: %v", err)
+	}
+
+	destDir := filepath.Join(dir, "dest")
+	if err := Unpack(archivePath, destDir, UnpackOptions{}); err != nil {
+		t.Fatalf("Unpack of a followed-symlink entry should succeed, got: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(destDir, "link.txt"))
+	if err != nil {
+		t.Fatalf("read unpacked link.txt: %v", err)
+	}
+	if string(got) != "payload" {
+		t.Fatalf("link.txt content = %q, want %q", got, "payload")
+	}
+}
+
+func TestPackDirLeavesNoArchiveOnMidStreamError(t *testing.T) {
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "src")
+	if err := os.Mkdir(srcDir, 0o755); err != nil {
+		t.Fatalf("mkdir srcDir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("data"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	archivePath := filepath.Join(dir, "out.tar.gz")
+
+	// An invalid compression level fails gzip.NewWriterLevel from inside
+	// packTar, after the sibling temp file has already been created.
+	invalidLevel := 999
+	err := PackDir(srcDir, archivePath, PackOptions{Compression: &invalidLevel})
+	if err == nil {
+		t.Fatal("expected PackDir to fail on an invalid compression level")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	for _, e := range entries {
+		if e.Name() != "src" {
+			t.Fatalf("PackDir left a stray file behind after a mid-stream error: %s", e.Name())
+		}
+	}
+}
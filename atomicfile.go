@@ -0,0 +1,54 @@
+package fileutils
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+)
+
+// withAtomicFile is the shared "sibling temp file -> Sync -> Close -> Rename"
+// dance used by CopyFileAtomic, CopyFileCtx and PackDir (the CSV writers and
+// Unpack write their files directly): it creates dst + ".part-<rand>",
+// hands it to write, and on success Syncs, Closes, runs the optional
+// finalize hook (e.g. chmod/chtimes on the temp path), and renames it into
+// place. If write, finalize, or any step in between fails, the temp file is
+// removed so a crash or mid-stream error never leaves a truncated dst behind.
+// write must not close f itself.
+func withAtomicFile(dst string, write func(f *os.File) error, finalize func(tmpPath string) error) error {
+	tmp := dst + fmt.Sprintf(".part-%d", rand.Int63())
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	cleanTmp := true
+	defer func() {
+		_ = f.Close()
+		if cleanTmp {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	if err := write(f); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if finalize != nil {
+		if err := finalize(tmp); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		return err
+	}
+	cleanTmp = false
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package fileutils
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// HashAlgo selects the digest used to verify a copy.
+type HashAlgo int
+
+const (
+	HashNone HashAlgo = iota
+	HashMD5
+	HashSHA1
+	HashSHA256
+	HashCRC32
+)
+
+// OverwritePolicy controls what CopyFileAtomic does when dst already exists.
+type OverwritePolicy int
+
+const (
+	OverwriteNever OverwritePolicy = iota
+	OverwriteAlways
+	OverwriteIfDifferentHash
+)
+
+// CopyOptions configures CopyFileAtomic.
+type CopyOptions struct {
+	Hash              HashAlgo
+	VerifyAfterCopy   bool
+	PreservePerm      bool
+	PreserveModTime   bool
+	Overwrite         OverwritePolicy
+	StabilityStrategy StabilityStrategy
+}
+
+// HashMismatchError is returned when a post-copy verification hash does not
+// match the source hash.
+type HashMismatchError struct {
+	Src  string
+	Dst  string
+	Want string
+	Got  string
+}
+
+func (e *HashMismatchError) Error() string {
+	return fmt.Sprintf("hash mismatch copying %s to %s: want %s, got %s", e.Src, e.Dst, e.Want, e.Got)
+}
+
+func newHasher(algo HashAlgo) (hash.Hash, error) {
+	switch algo {
+	case HashNone:
+		return nil, nil
+	case HashMD5:
+		return md5.New(), nil
+	case HashSHA1:
+		return sha1.New(), nil
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashCRC32:
+		return crc32.NewIEEE(), nil
+	default:
+		return nil, fmt.Errorf("unknown hash algo: %d", algo)
+	}
+}
+
+// FileHash computes the digest of the file at path using algo.
+func FileHash(path string, algo HashAlgo) (string, error) {
+	h, err := newHasher(algo)
+	if err != nil {
+		return "", err
+	}
+	if h == nil {
+		return "", nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// CopyFileAtomic copies src to dst via a sibling temp file, renaming it into
+// place only once the data has been written and synced to disk. This avoids
+// leaving a truncated dst behind if the process dies mid-copy.
+func CopyFileAtomic(src, dst string, opts CopyOptions) error {
+	if !waitStable(src, opts.StabilityStrategy) {
+		return fmt.Errorf("source not stable: %s", src)
+	}
+
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dst); err == nil {
+		switch opts.Overwrite {
+		case OverwriteNever:
+			return fmt.Errorf("destination already exists: %s", dst)
+		case OverwriteIfDifferentHash:
+			algo := opts.Hash
+			if algo == HashNone {
+				algo = HashSHA256
+			}
+			srcHash, err := FileHash(src, algo)
+			if err != nil {
+				return err
+			}
+			dstHash, err := FileHash(dst, algo)
+			if err != nil {
+				return err
+			}
+			if srcHash == dstHash {
+				return nil
+			}
+		case OverwriteAlways:
+			// fall through and overwrite
+		}
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	hashAlgo := effectiveHashAlgo(opts)
+	h, err := newHasher(hashAlgo)
+	if err != nil {
+		return err
+	}
+
+	err = withAtomicFile(dst, func(out *os.File) error {
+		var w io.Writer = out
+		if h != nil {
+			w = io.MultiWriter(out, h)
+		}
+		_, err := io.Copy(w, in)
+		return err
+	}, func(tmpPath string) error {
+		if opts.PreservePerm {
+			if err := os.Chmod(tmpPath, srcInfo.Mode()); err != nil {
+				return err
+			}
+		}
+		if opts.PreserveModTime {
+			if err := os.Chtimes(tmpPath, srcInfo.ModTime(), srcInfo.ModTime()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts.VerifyAfterCopy {
+		want := hex.EncodeToString(h.Sum(nil))
+		if err := verifyDstHash(src, dst, want, hashAlgo); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// effectiveHashAlgo is the hash algorithm CopyFileAtomic actually uses for a
+// given CopyOptions. VerifyAfterCopy without an explicit Hash would
+// otherwise silently skip verification (HashNone never computes a digest),
+// which is precisely the silent-corruption scenario this option exists to
+// close, so it defaults to HashSHA256, the same default OverwriteIfDifferentHash
+// already uses a few lines up.
+func effectiveHashAlgo(opts CopyOptions) HashAlgo {
+	if opts.VerifyAfterCopy && opts.Hash == HashNone {
+		return HashSHA256
+	}
+	return opts.Hash
+}
+
+// verifyDstHash re-hashes dst and compares it against want. On mismatch, dst
+// is already in place post-rename, so a caller must never see the corrupted
+// bytes after a non-nil error: it is removed before returning HashMismatchError.
+func verifyDstHash(src, dst, want string, algo HashAlgo) error {
+	got, err := FileHash(dst, algo)
+	if err != nil {
+		return err
+	}
+	if want != got {
+		_ = os.Remove(dst)
+		return &HashMismatchError{Src: src, Dst: dst, Want: want, Got: got}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package fileutils
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// chunk size defaults and safe bounds for CopyFileCtx.
+const (
+	defaultCopyChunkKB = 64
+	minCopyChunkKB     = 4
+	maxCopyChunkKB     = 65536
+)
+
+// progressThrottle caps how often the progress callback fires during a copy.
+const progressThrottle = 200 * time.Millisecond
+
+// CopyFileCtx copies src to dst in chunks (FILEUTILS_COPY_CHUNK_KB KiB,
+// default 64 KiB), checking ctx between chunks so a long copy can be
+// cancelled, and invoking progress (if non-nil) with bytes copied so far and
+// the total size, throttled to at most once per progressThrottle while
+// copying, plus one final report once the whole file is copied. The copy
+// goes through a sibling temp file renamed into place on success; if ctx is
+// cancelled or an error occurs, the temp file is removed so no partial write
+// is left behind.
+func CopyFileCtx(ctx context.Context, src, dst string, progress func(copied, total int64)) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	total := srcInfo.Size()
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	chunkKB := getenvInt("FILEUTILS_COPY_CHUNK_KB", defaultCopyChunkKB, minCopyChunkKB, maxCopyChunkKB)
+	buf := make([]byte, chunkKB*1024)
+
+	var copied int64
+	lastReport := time.Time{}
+	report := func(force bool) {
+		if progress == nil {
+			return
+		}
+		if !force && time.Since(lastReport) < progressThrottle {
+			return
+		}
+		progress(copied, total)
+		lastReport = time.Now()
+	}
+
+	return withAtomicFile(dst, func(out *os.File) error {
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			n, readErr := in.Read(buf)
+			if n > 0 {
+				if _, writeErr := out.Write(buf[:n]); writeErr != nil {
+					return writeErr
+				}
+				copied += int64(n)
+				report(false)
+			}
+			if readErr == io.EOF {
+				break
+			}
+			if readErr != nil {
+				return readErr
+			}
+		}
+		report(true)
+		return nil
+	}, nil)
+}
+
+// SubFileListCtx is SubFileList with ctx support: the walk aborts as soon as
+// ctx is done, returning ctx.Err().
+func SubFileListCtx(ctx context.Context, path, prefix, suffix string) ([]string, error) {
+	var listOfFiles []string
+
+	err := filepath.Walk(path,
+		func(folderAndPath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if strings.HasPrefix(info.Name(), prefix) && strings.HasSuffix(info.Name(), suffix) {
+				listOfFiles = append(listOfFiles, folderAndPath)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return listOfFiles, nil
+}
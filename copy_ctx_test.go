@@ -0,0 +1,78 @@
+package fileutils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyFileCtxRemovesTempFileOnCancellation(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.bin")
+	dst := filepath.Join(dir, "dst.bin")
+
+	if err := os.WriteFile(src, make([]byte, 4096), 0o644); err != nil {
+		t.Fatalf("write src: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := CopyFileCtx(ctx, src, dst, nil); err == nil {
+		t.Fatal("expected CopyFileCtx to fail on an already-cancelled context")
+	}
+
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("expected dst not to exist, stat err = %v", err)
+	}
+
+	matches, err := filepath.Glob(dst + ".part-*")
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("expected no leftover temp files, found %v", matches)
+	}
+}
+
+func TestCopyFileCtxCopiesFileAndReportsProgress(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.bin")
+	dst := filepath.Join(dir, "dst.bin")
+
+	content := make([]byte, 256*1024)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	if err := os.WriteFile(src, content, 0o644); err != nil {
+		t.Fatalf("write src: %v", err)
+	}
+
+	var lastCopied, lastTotal int64
+	calls := 0
+	err := CopyFileCtx(context.Background(), src, dst, func(copied, total int64) {
+		calls++
+		lastCopied, lastTotal = copied, total
+	})
+	if err != nil {
+		t.Fatalf("CopyFileCtx: %v", err)
+	}
+
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("read dst: %v", err)
+	}
+	if string(got) != string(content) {
+		t.Fatal("dst content does not match src")
+	}
+	if calls == 0 {
+		t.Fatal("expected progress to be reported at least once")
+	}
+	if lastTotal != int64(len(content)) {
+		t.Fatalf("last reported total = %d, want %d", lastTotal, len(content))
+	}
+	if lastCopied != int64(len(content)) {
+		t.Fatalf("last reported copied = %d, want %d", lastCopied, len(content))
+	}
+}
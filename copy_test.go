@@ -0,0 +1,108 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyDstHashRemovesCorruptedDst(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "dst.txt")
+
+	if err := os.WriteFile(dst, []byte("corrupted bytes"), 0o644); err != nil {
+		t.Fatalf("write dst: %v", err)
+	}
+
+	err := verifyDstHash("src.txt", dst, "deadbeef", HashSHA256)
+	if err == nil {
+		t.Fatal("expected a HashMismatchError, got nil")
+	}
+
+	var mismatch *HashMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected *HashMismatchError, got %T: %v", err, err)
+	}
+	if mismatch.Src != "src.txt" || mismatch.Dst != dst {
+		t.Fatalf("unexpected error fields: %+v", mismatch)
+	}
+
+	if _, statErr := os.Stat(dst); !os.IsNotExist(statErr) {
+		t.Fatalf("expected dst to be removed after a hash mismatch, stat err: %v", statErr)
+	}
+}
+
+func TestEffectiveHashAlgoDefaultsWhenVerifyRequestedWithoutHash(t *testing.T) {
+	cases := []struct {
+		name string
+		opts CopyOptions
+		want HashAlgo
+	}{
+		{"verify without hash defaults to sha256", CopyOptions{VerifyAfterCopy: true}, HashSHA256},
+		{"verify with explicit hash keeps it", CopyOptions{VerifyAfterCopy: true, Hash: HashMD5}, HashMD5},
+		{"no verify leaves hash none", CopyOptions{}, HashNone},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := effectiveHashAlgo(c.opts); got != c.want {
+				t.Fatalf("effectiveHashAlgo(%+v) = %v, want %v", c.opts, got, c.want)
+			}
+		})
+	}
+}
+
+func TestCopyFileAtomicVerifyAfterCopySucceeds(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	dst := filepath.Join(dir, "dst.txt")
+
+	if err := os.WriteFile(src, []byte("hello world"), 0o644); err != nil {
+		t.Fatalf("write src: %v", err)
+	}
+
+	err := CopyFileAtomic(src, dst, CopyOptions{
+		Hash:            HashSHA256,
+		VerifyAfterCopy: true,
+		Overwrite:       OverwriteAlways,
+	})
+	if err != nil {
+		t.Fatalf("CopyFileAtomic: %v", err)
+	}
+
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("read dst: %v", err)
+	}
+	if string(got) != "hello world" {
+		t.Fatalf("dst content = %q, want %q", got, "hello world")
+	}
+}
+
+func TestCopyFileAtomicVerifyAfterCopyWithoutExplicitHash(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	dst := filepath.Join(dir, "dst.txt")
+
+	if err := os.WriteFile(src, []byte("hello world"), 0o644); err != nil {
+		t.Fatalf("write src: %v", err)
+	}
+
+	// Hash is left at its zero value (HashNone); VerifyAfterCopy alone must
+	// still trigger verification rather than silently no-op'ing.
+	err := CopyFileAtomic(src, dst, CopyOptions{
+		VerifyAfterCopy: true,
+		Overwrite:       OverwriteAlways,
+	})
+	if err != nil {
+		t.Fatalf("CopyFileAtomic: %v", err)
+	}
+
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("read dst: %v", err)
+	}
+	if string(got) != "hello world" {
+		t.Fatalf("dst content = %q, want %q", got, "hello world")
+	}
+}
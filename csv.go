@@ -0,0 +1,283 @@
+package fileutils
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// CsvIter streams a CSV file one record at a time, so gigabyte files can be
+// processed without loading the whole thing into memory. Create one with
+// CsvIterator and drive it with Next/Row/RawRecord until Next returns false,
+// then check Err for anything other than a clean end-of-file.
+type CsvIter struct {
+	file   *os.File
+	reader *csv.Reader
+	header []string
+	record []string
+	err    error
+}
+
+// CsvIterator opens filename and returns a CsvIter positioned before the
+// first data row; the header row is consumed and exposed via Header().
+func CsvIterator(filename string, sep rune) (*CsvIter, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	r := csv.NewReader(f)
+	r.Comma = sep
+
+	header, err := r.Read()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &CsvIter{file: f, reader: r, header: header}, nil
+}
+
+// Header returns the column names from the first row.
+func (it *CsvIter) Header() []string {
+	return it.header
+}
+
+// Next advances to the next record, returning false at EOF or on error.
+// Check Err after Next returns false to distinguish the two.
+func (it *CsvIter) Next() bool {
+	record, err := it.reader.Read()
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.record = record
+	return true
+}
+
+// Row returns the current record as a map keyed by header column.
+func (it *CsvIter) Row() map[string]string {
+	dict := make(map[string]string, len(it.header))
+	for i := range it.header {
+		if i < len(it.record) {
+			dict[it.header[i]] = it.record[i]
+		}
+	}
+	return dict
+}
+
+// RawRecord returns the current record as the raw, ordered field slice.
+func (it *CsvIter) RawRecord() []string {
+	return it.record
+}
+
+// Err returns the first non-EOF error encountered while reading.
+func (it *CsvIter) Err() error {
+	return it.err
+}
+
+// Close releases the underlying file.
+func (it *CsvIter) Close() error {
+	return it.file.Close()
+}
+
+// CsvWriteOptions configures MapToCsv.
+type CsvWriteOptions struct {
+	Separator  rune
+	Header     []string
+	QuoteAll   bool
+	LineEnding string
+	Append     bool
+}
+
+// MapToCsv writes rows to filename, the inverse of CsvToMap: MapToCsv(f,
+// CsvToMap(f)...) round-trips. Header is taken from opts.Header if set,
+// otherwise derived from the sorted union of keys across rows. When
+// opts.Append is set and filename already has a matching header, rows are
+// appended without rewriting it.
+func MapToCsv(filename string, rows []map[string]string, opts CsvWriteOptions) error {
+	sep := opts.Separator
+	if sep == 0 {
+		sep = ','
+	}
+	lineEnding := opts.LineEnding
+	if lineEnding == "" {
+		lineEnding = "\n"
+	}
+
+	header := opts.Header
+	if header == nil {
+		header = unionSortedKeys(rows)
+	}
+
+	writeHeader := true
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if opts.Append {
+		if existing, err := readCsvHeaderLine(filename, sep); err == nil {
+			if existing != strings.Join(header, string(sep)) {
+				return fmt.Errorf("append header mismatch: file has %q, want %q", existing, strings.Join(header, string(sep)))
+			}
+			writeHeader = false
+			flags = os.O_APPEND | os.O_WRONLY
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(filename, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+
+	if writeHeader {
+		if err := writeCsvRecord(w, header, sep, opts.QuoteAll, lineEnding); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for i, col := range header {
+			record[i] = row[col]
+		}
+		if err := writeCsvRecord(w, record, sep, opts.QuoteAll, lineEnding); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// CsvTransform streams rows from in through fn and writes the result to out,
+// for ETL-style pipelines that shouldn't hold the whole file in memory. The
+// output header is taken from the first transformed row's sorted keys.
+func CsvTransform(in, out string, sep rune, fn func(row map[string]string) (map[string]string, error)) error {
+	it, err := CsvIterator(in, sep)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	outFile, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	w := bufio.NewWriter(outFile)
+	defer w.Flush()
+
+	var header []string
+	for it.Next() {
+		row, err := fn(it.Row())
+		if err != nil {
+			return err
+		}
+		if header == nil {
+			header = sortedKeys(row)
+			if err := writeCsvRecord(w, header, sep, false, "\n"); err != nil {
+				return err
+			}
+		}
+		record := make([]string, len(header))
+		for i, col := range header {
+			record[i] = row[col]
+		}
+		if err := writeCsvRecord(w, record, sep, false, "\n"); err != nil {
+			return err
+		}
+	}
+	if it.Err() != nil {
+		return it.Err()
+	}
+
+	return w.Flush()
+}
+
+// readCsvHeaderLine returns the first record of filename, parsed with sep
+// (so quoted fields are unquoted) and joined back with sep, used to check
+// that an append target's header matches what we're about to write.
+func readCsvHeaderLine(filename string, sep rune) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comma = sep
+	r.FieldsPerRecord = -1
+	fields, err := r.Read()
+	if err == io.EOF {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(fields, string(sep)), nil
+}
+
+func unionSortedKeys(rows []map[string]string) []string {
+	set := map[string]struct{}{}
+	for _, row := range rows {
+		for k := range row {
+			set[k] = struct{}{}
+		}
+	}
+	return sortedKeysOf(set)
+}
+
+func sortedKeys(row map[string]string) []string {
+	set := map[string]struct{}{}
+	for k := range row {
+		set[k] = struct{}{}
+	}
+	return sortedKeysOf(set)
+}
+
+func sortedKeysOf(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeCsvRecord writes one CSV record using sep as the field separator and
+// lineEnding as the terminator. When quoteAll is set every field is quoted;
+// otherwise only fields containing the separator, a quote, or a newline are.
+func writeCsvRecord(w io.Writer, record []string, sep rune, quoteAll bool, lineEnding string) error {
+	var b strings.Builder
+	for i, field := range record {
+		if i > 0 {
+			b.WriteRune(sep)
+		}
+		if quoteAll || needsCsvQuoting(field, sep) {
+			b.WriteByte('"')
+			b.WriteString(strings.ReplaceAll(field, `"`, `""`))
+			b.WriteByte('"')
+		} else {
+			b.WriteString(field)
+		}
+	}
+	b.WriteString(lineEnding)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func needsCsvQuoting(field string, sep rune) bool {
+	return strings.ContainsRune(field, sep) || strings.ContainsAny(field, "\"\n\r")
+}
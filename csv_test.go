@@ -0,0 +1,134 @@
+package fileutils
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMapToCsvAndCsvToMapRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.csv")
+
+	rows := []map[string]string{
+		{"id": "1", "name": "alice"},
+		{"id": "2", "name": "bob"},
+	}
+
+	if err := MapToCsv(path, rows, CsvWriteOptions{Header: []string{"id", "name"}}); err != nil {
+		t.Fatalf("MapToCsv: %v", err)
+	}
+
+	got, err := CsvToMap(path, ',')
+	if err != nil {
+		t.Fatalf("CsvToMap: %v", err)
+	}
+	if !reflect.DeepEqual(got, rows) {
+		t.Fatalf("round trip mismatch: got %v, want %v", got, rows)
+	}
+}
+
+func TestMapToCsvDerivesHeaderFromSortedKeyUnion(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.csv")
+
+	rows := []map[string]string{
+		{"name": "alice", "id": "1"},
+	}
+	if err := MapToCsv(path, rows, CsvWriteOptions{}); err != nil {
+		t.Fatalf("MapToCsv: %v", err)
+	}
+
+	it, err := CsvIterator(path, ',')
+	if err != nil {
+		t.Fatalf("CsvIterator: %v", err)
+	}
+	defer it.Close()
+
+	want := []string{"id", "name"}
+	sort.Strings(want)
+	if !reflect.DeepEqual(it.Header(), want) {
+		t.Fatalf("Header() = %v, want %v", it.Header(), want)
+	}
+}
+
+func TestMapToCsvAppendMatchingHeaderAppendsRows(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.csv")
+
+	opts := CsvWriteOptions{Header: []string{"id", "name"}}
+	if err := MapToCsv(path, []map[string]string{{"id": "1", "name": "alice"}}, opts); err != nil {
+		t.Fatalf("initial MapToCsv: %v", err)
+	}
+
+	opts.Append = true
+	if err := MapToCsv(path, []map[string]string{{"id": "2", "name": "bob"}}, opts); err != nil {
+		t.Fatalf("append MapToCsv: %v", err)
+	}
+
+	got, err := CsvToMap(path, ',')
+	if err != nil {
+		t.Fatalf("CsvToMap: %v", err)
+	}
+	want := []map[string]string{
+		{"id": "1", "name": "alice"},
+		{"id": "2", "name": "bob"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestMapToCsvAppendHeaderMismatchIsRejected(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.csv")
+
+	if err := MapToCsv(path, []map[string]string{{"id": "1", "name": "alice"}}, CsvWriteOptions{Header: []string{"id", "name"}}); err != nil {
+		t.Fatalf("initial MapToCsv: %v", err)
+	}
+
+	err := MapToCsv(path, []map[string]string{{"id": "2", "email": "bob@example.com"}}, CsvWriteOptions{Header: []string{"id", "email"}, Append: true})
+	if err == nil {
+		t.Fatal("expected an error appending rows with a mismatched header")
+	}
+
+	got, readErr := CsvToMap(path, ',')
+	if readErr != nil {
+		t.Fatalf("CsvToMap: %v", readErr)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected the rejected append to leave the file untouched, got %v", got)
+	}
+}
+
+func TestCsvIteratorStreamsRecordsInOrder(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.csv")
+
+	rows := []map[string]string{
+		{"id": "1", "name": "alice"},
+		{"id": "2", "name": "bob"},
+		{"id": "3", "name": "carol"},
+	}
+	if err := MapToCsv(path, rows, CsvWriteOptions{Header: []string{"id", "name"}}); err != nil {
+		t.Fatalf("MapToCsv: %v", err)
+	}
+
+	it, err := CsvIterator(path, ',')
+	if err != nil {
+		t.Fatalf("CsvIterator: %v", err)
+	}
+	defer it.Close()
+
+	var got []map[string]string
+	for it.Next() {
+		got = append(got, it.Row())
+	}
+	if it.Err() != nil {
+		t.Fatalf("iteration error: %v", it.Err())
+	}
+	if !reflect.DeepEqual(got, rows) {
+		t.Fatalf("got %v, want %v", got, rows)
+	}
+}
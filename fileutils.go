@@ -1,13 +1,16 @@
 package fileutils
 
 import (
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -89,48 +92,90 @@ func CopyFileDepr(src, dst string) error {
 }
 
 // MoveFile moves a file from source location to destination.
+// When src and dst live on different devices, os.Rename fails with EXDEV;
+// MoveFile falls back to a copy-then-delete in that case. Both the rename
+// attempt and the fallback phases are retried on transient errors.
 func MoveFile(src, dst string) error {
-	err := os.Rename(src, dst)
-	if err != nil {
+	retrier := DefaultRetrier()
+
+	return moveFileCore(retrier, func() error {
+		return os.Rename(src, dst)
+	}, func() error {
+		if err := retrier.Do(func() error {
+			return CopyFileAtomic(src, dst, CopyOptions{Overwrite: OverwriteAlways})
+		}); err != nil {
+			return err
+		}
+		return retrier.Do(func() error {
+			return os.Remove(src)
+		})
+	})
+}
+
+// moveFileCore implements MoveFile's EXDEV fallback decision, with rename
+// and copyAndDelete injected so the decision can be tested without needing
+// two real devices to provoke a genuine EXDEV.
+func moveFileCore(retrier *Retrier, rename, copyAndDelete func() error) error {
+	err := retrier.Do(rename)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
 		return err
 	}
-	return err
+	return copyAndDelete()
 }
 
-// CopyFile waits until src looks stable (size stops changing) and then copies it to dst.
-// Simple guard against processing a still-growing file. No atomic rename involved.
+// CopyFile waits until src looks stable and then copies it to dst. Stability
+// is detected via fsnotify with a poll-based fallback (see StabilityAuto);
+// the copy goes through a sibling temp file that is renamed into place once
+// synced, so a crash mid-copy never leaves a truncated dst behind.
 func CopyFile(src, dst string) error {
-	attempts := getenvInt("FILEUTILS_STABLE_ATTEMPTS", defaultAttempts, minAttempts, maxAttempts)
-	settle := getenvDur("FILEUTILS_STABLE_SETTLE_MS", defaultSettleMS, minSettleMS, maxSettleMS)
-
-	if !waitStableSize(src, attempts, settle) {
+	if !waitStable(src, StabilityAuto) {
 		return fmt.Errorf("source not stable: %s", src)
 	}
 
-	in, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer in.Close()
+	return DefaultRetrier().Do(func() error {
+		return CopyFileCtx(context.Background(), src, dst, nil)
+	})
+}
 
-	out, err := os.Create(dst) // overwrite if exists
+// CsvToMap converts the csv data (with header) into map, with header parts as keys
+func CsvToMap(fileName string, separator rune) ([]map[string]string, error) {
+	data, err := os.Open(fileName)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() { _ = out.Close() }()
+	defer data.Close()
 
-	if _, err = io.Copy(out, in); err != nil {
-		return err
+	r := csv.NewReader(data)
+	r.Comma = separator
+	rows := []map[string]string{}
+	var header []string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			header = record
+		} else {
+			dict := map[string]string{}
+			for i := range header {
+				dict[header[i]] = record[i]
+			}
+			rows = append(rows, dict)
+		}
 	}
-
-	// Optional: _ = out.Sync()
-	_ = out.Sync()
-
-	return nil
+	return rows, nil
 }
 
-// CsvToMap converts the csv data (with header) into map, with header parts as keys
-func CsvToMap(fileName string, separator rune) ([]map[string]string, error) {
+// CsvToMapCtx is CsvToMap with ctx support: reading aborts between records as
+// soon as ctx is done, returning ctx.Err().
+func CsvToMapCtx(ctx context.Context, fileName string, separator rune) ([]map[string]string, error) {
 	data, err := os.Open(fileName)
 	if err != nil {
 		return nil, err
@@ -142,6 +187,10 @@ func CsvToMap(fileName string, separator rune) ([]map[string]string, error) {
 	rows := []map[string]string{}
 	var header []string
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		record, err := r.Read()
 		if err == io.EOF {
 			break
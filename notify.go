@@ -0,0 +1,119 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// StabilityStrategy selects how CopyFile/CopyFileAtomic decide a source file
+// has stopped being written to before it is copied.
+type StabilityStrategy int
+
+const (
+	// StabilityAuto tries fsnotify first and falls back to size-polling if
+	// the watcher can't be initialized (e.g. on filesystems without inotify
+	// support, such as some SMB mounts). It also honors FILEUTILS_STABLE_STRATEGY.
+	StabilityAuto StabilityStrategy = iota
+	StabilityPoll
+	StabilityNotify
+)
+
+// fsnotify quiet-period defaults and safe bounds.
+const (
+	defaultStableQuietMS = 300
+	minStableQuietMS     = 50
+	maxStableQuietMS     = 10000
+)
+
+// errStableTimeout is wrapped by waitStableFsnotify's error when the timeout
+// elapses before the file goes quiet.
+var errStableTimeout = errors.New("timed out waiting to stabilize")
+
+// waitStable decides whether path looks stable enough to copy, dispatching
+// to waitStableFsnotify or the existing waitStableSize poll per strategy.
+func waitStable(path string, strategy StabilityStrategy) bool {
+	attempts := getenvInt("FILEUTILS_STABLE_ATTEMPTS", defaultAttempts, minAttempts, maxAttempts)
+	settle := getenvDur("FILEUTILS_STABLE_SETTLE_MS", defaultSettleMS, minSettleMS, maxSettleMS)
+
+	if strategy == StabilityAuto {
+		strategy = stabilityStrategyFromEnv()
+	}
+
+	if strategy == StabilityPoll {
+		return waitStableSize(path, attempts, settle)
+	}
+
+	quiet := getenvDur("FILEUTILS_STABLE_QUIET_MS", defaultStableQuietMS, minStableQuietMS, maxStableQuietMS)
+	timeout := time.Duration(attempts) * settle
+
+	if err := waitStableFsnotify(path, quiet, timeout); err == nil {
+		return true
+	} else if strategy == StabilityNotify || errors.Is(err, errStableTimeout) {
+		return false
+	}
+
+	// Auto falls back to polling when fsnotify itself couldn't be used; a
+	// timeout means the file kept changing and is reported as not stable.
+	return waitStableSize(path, attempts, settle)
+}
+
+func stabilityStrategyFromEnv() StabilityStrategy {
+	switch os.Getenv("FILEUTILS_STABLE_STRATEGY") {
+	case "poll":
+		return StabilityPoll
+	case "notify":
+		return StabilityNotify
+	default:
+		return StabilityAuto
+	}
+}
+
+// waitStableFsnotify watches path's parent directory and considers path
+// stable once quietPeriod has elapsed with no Write or Create event for it,
+// returning an error if timeout elapses first or the watcher can't be set up.
+func waitStableFsnotify(path string, quietPeriod, timeout time.Duration) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	target := filepath.Clean(path)
+	deadline := time.After(timeout)
+	quiet := time.NewTimer(quietPeriod)
+	defer quiet.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return fmt.Errorf("fsnotify watcher closed for %s", path)
+			}
+			isRelevant := event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Create == fsnotify.Create
+			if filepath.Clean(event.Name) == target && isRelevant {
+				if !quiet.Stop() {
+					<-quiet.C
+				}
+				quiet.Reset(quietPeriod)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return fmt.Errorf("fsnotify watcher closed for %s", path)
+			}
+			return err
+		case <-quiet.C:
+			return nil
+		case <-deadline:
+			return fmt.Errorf("%s: %w", path, errStableTimeout)
+		}
+	}
+}
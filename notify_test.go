@@ -0,0 +1,83 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWaitStableFsnotifyReturnsNilOnceQuiet(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	start := time.Now()
+	err := waitStableFsnotify(path, 50*time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatalf("waitStableFsnotify: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("took %v, expected to settle close to the quiet period", elapsed)
+	}
+}
+
+func TestWaitStableFsnotifyResetsQuietPeriodOnWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = os.WriteFile(path, []byte("hello again"), 0o644)
+		close(done)
+	}()
+
+	start := time.Now()
+	err := waitStableFsnotify(path, 150*time.Millisecond, 2*time.Second)
+	<-done
+	if err != nil {
+		t.Fatalf("waitStableFsnotify: %v", err)
+	}
+	// Without the reset the quiet period would end at ~150ms; the write at
+	// ~100ms pushes it out to ~250ms.
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("took %v, expected the mid-flight write to extend the quiet period", elapsed)
+	}
+}
+
+func TestWaitStableFsnotifyTimesOut(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = os.WriteFile(path, []byte("churn"), 0o644)
+				time.Sleep(20 * time.Millisecond)
+			}
+		}
+	}()
+
+	err := waitStableFsnotify(path, 300*time.Millisecond, 150*time.Millisecond)
+	close(stop)
+	<-done
+
+	if err == nil {
+		t.Fatal("expected waitStableFsnotify to time out while the file keeps changing")
+	}
+}
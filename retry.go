@@ -0,0 +1,162 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"syscall"
+	"time"
+)
+
+// retry defaults and safe bounds, consistent with the FILEUTILS_STABLE_* knobs.
+const (
+	defaultRetryAttempts = 5
+	minRetryAttempts     = 1
+	maxRetryAttempts     = 20
+
+	defaultRetryBaseMS = 200
+	minRetryBaseMS     = 10
+	maxRetryBaseMS     = 10000
+
+	defaultRetryMaxMS = 5000
+	minRetryMaxMS     = 10
+	maxRetryMaxMS     = 60000
+)
+
+// ErrDeviceNotReady indicates the underlying device or share was not ready,
+// e.g. a network mount that has not finished attaching yet.
+var ErrDeviceNotReady = errors.New("device not ready")
+
+// Retrier runs an operation with exponential backoff until it succeeds,
+// ShouldRetry rejects the error, or MaxAttempts is exhausted.
+type Retrier struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64
+	ShouldRetry    func(error) bool
+}
+
+// RetryError is returned when a Retrier exhausts MaxAttempts without success.
+type RetryError struct {
+	Attempts int
+	LastErr  error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("gave up after %d attempts: %v", e.Attempts, e.LastErr)
+}
+
+func (e *RetryError) Unwrap() error { return e.LastErr }
+
+// DefaultRetrier builds a Retrier from the FILEUTILS_RETRY_* env vars (or
+// their built-in defaults), classifying transient IO errors as retryable.
+func DefaultRetrier() *Retrier {
+	return &Retrier{
+		MaxAttempts:    getenvInt("FILEUTILS_RETRY_ATTEMPTS", defaultRetryAttempts, minRetryAttempts, maxRetryAttempts),
+		InitialBackoff: getenvDur("FILEUTILS_RETRY_BASE_MS", defaultRetryBaseMS, minRetryBaseMS, maxRetryBaseMS),
+		MaxBackoff:     getenvDur("FILEUTILS_RETRY_MAX_MS", defaultRetryMaxMS, minRetryMaxMS, maxRetryMaxMS),
+		Multiplier:     2.0,
+		Jitter:         0.2,
+		ShouldRetry:    IsRetryable,
+	}
+}
+
+// Do runs op, retrying on retryable errors per the Retrier's backoff policy.
+// It returns nil on success, the original error if ShouldRetry rejects it,
+// or a *RetryError once MaxAttempts is exhausted.
+func (r *Retrier) Do(op func() error) error {
+	shouldRetry := r.ShouldRetry
+	if shouldRetry == nil {
+		shouldRetry = IsRetryable
+	}
+
+	delay := r.InitialBackoff
+	var lastErr error
+	for attempt := 1; attempt <= r.MaxAttempts; attempt++ {
+		lastErr = op()
+		if lastErr == nil {
+			return nil
+		}
+		if !shouldRetry(lastErr) {
+			return lastErr
+		}
+		if attempt == r.MaxAttempts {
+			break
+		}
+
+		time.Sleep(delay + jitter(delay, r.Jitter))
+		delay = time.Duration(float64(delay) * r.Multiplier)
+		if delay > r.MaxBackoff {
+			delay = r.MaxBackoff
+		}
+	}
+	return &RetryError{Attempts: r.MaxAttempts, LastErr: lastErr}
+}
+
+// jitter returns a random offset uniformly sampled from [-factor*delay, +factor*delay].
+func jitter(delay time.Duration, factor float64) time.Duration {
+	if factor <= 0 {
+		return 0
+	}
+	spread := float64(delay) * factor
+	return time.Duration((rand.Float64()*2 - 1) * spread)
+}
+
+// IsRetryable classifies errors worth retrying: transient errno conditions
+// such as EBUSY/EAGAIN, ErrDeviceNotReady, a platform sharing-violation, and
+// an *os.PathError wrapping any of those.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrDeviceNotReady) {
+		return true
+	}
+
+	cause := err
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		cause = pathErr.Err
+	}
+
+	var errno syscall.Errno
+	if errors.As(cause, &errno) {
+		if errno == syscall.EBUSY || errno == syscall.EAGAIN {
+			return true
+		}
+	}
+
+	return isRetryablePlatform(cause)
+}
+
+// OpenRetry opens path for reading, retrying on transient errors such as a
+// share or antivirus lock that releases within a few attempts.
+func OpenRetry(path string) (*os.File, error) {
+	var f *os.File
+	err := DefaultRetrier().Do(func() error {
+		var openErr error
+		f, openErr = os.Open(path)
+		return openErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// StatRetry stats path, retrying on transient errors.
+func StatRetry(path string) (os.FileInfo, error) {
+	var fi os.FileInfo
+	err := DefaultRetrier().Do(func() error {
+		var statErr error
+		fi, statErr = os.Stat(path)
+		return statErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fi, nil
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package fileutils
+
+// isRetryablePlatform has no additional platform-specific retryable errors
+// outside the common errno classification.
+func isRetryablePlatform(err error) bool {
+	return false
+}
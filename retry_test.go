@@ -0,0 +1,142 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetrierDoExhaustsToRetryError(t *testing.T) {
+	r := &Retrier{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     2,
+		Jitter:         0,
+		ShouldRetry:    func(error) bool { return true },
+	}
+
+	boom := errors.New("boom")
+	attempts := 0
+	err := r.Do(func() error {
+		attempts++
+		return boom
+	})
+
+	if attempts != 3 {
+		t.Fatalf("op ran %d times, want 3", attempts)
+	}
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("expected *RetryError, got %T: %v", err, err)
+	}
+	if retryErr.Attempts != 3 {
+		t.Fatalf("RetryError.Attempts = %d, want 3", retryErr.Attempts)
+	}
+	if !errors.Is(retryErr, boom) {
+		t.Fatalf("expected RetryError to unwrap to the last error, got %v", retryErr.LastErr)
+	}
+}
+
+func TestRetrierDoStopsOnNonRetryableError(t *testing.T) {
+	r := &Retrier{
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     2,
+		ShouldRetry:    func(error) bool { return false },
+	}
+
+	boom := errors.New("boom")
+	attempts := 0
+	err := r.Do(func() error {
+		attempts++
+		return boom
+	})
+
+	if attempts != 1 {
+		t.Fatalf("op ran %d times, want 1", attempts)
+	}
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected the original error back unwrapped, got %v", err)
+	}
+}
+
+func TestRetrierDoSucceedsAfterTransientErrors(t *testing.T) {
+	r := &Retrier{
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     2,
+		ShouldRetry:    func(error) bool { return true },
+	}
+
+	attempts := 0
+	err := r.Do(func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("op ran %d times, want 3", attempts)
+	}
+}
+
+func TestIsRetryableClassifiesEBUSYAndPathError(t *testing.T) {
+	if !IsRetryable(&os.PathError{Op: "open", Path: "x", Err: syscall.EBUSY}) {
+		t.Fatal("expected an *os.PathError wrapping EBUSY to be retryable")
+	}
+	if !IsRetryable(ErrDeviceNotReady) {
+		t.Fatal("expected ErrDeviceNotReady to be retryable")
+	}
+	if IsRetryable(errors.New("permission denied")) {
+		t.Fatal("expected an unrelated error not to be retryable")
+	}
+}
+
+func TestMoveFileCoreFallsBackOnEXDEV(t *testing.T) {
+	retrier := &Retrier{MaxAttempts: 1, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1, ShouldRetry: func(error) bool { return false }}
+
+	fallbackCalled := false
+	err := moveFileCore(retrier, func() error {
+		return &os.LinkError{Op: "rename", Old: "a", New: "b", Err: syscall.EXDEV}
+	}, func() error {
+		fallbackCalled = true
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("moveFileCore: %v", err)
+	}
+	if !fallbackCalled {
+		t.Fatal("expected the copy-then-delete fallback to run on EXDEV")
+	}
+}
+
+func TestMoveFileCoreDoesNotFallBackOnOtherErrors(t *testing.T) {
+	retrier := &Retrier{MaxAttempts: 1, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1, ShouldRetry: func(error) bool { return false }}
+
+	notExist := &os.PathError{Op: "rename", Path: "a", Err: syscall.ENOENT}
+	fallbackCalled := false
+	err := moveFileCore(retrier, func() error {
+		return notExist
+	}, func() error {
+		fallbackCalled = true
+		return nil
+	})
+
+	if !errors.Is(err, notExist) {
+		t.Fatalf("expected the original error back, got %v", err)
+	}
+	if fallbackCalled {
+		t.Fatal("expected the fallback not to run for a non-EXDEV error")
+	}
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package fileutils
+
+import "syscall"
+
+// windows sharing-violation errno, returned e.g. when antivirus holds a lock.
+const errnoSharingViolation = syscall.Errno(32)
+
+func isRetryablePlatform(err error) bool {
+	var errno syscall.Errno
+	if e, ok := err.(syscall.Errno); ok {
+		errno = e
+	} else {
+		return false
+	}
+	return errno == errnoSharingViolation
+}